@@ -0,0 +1,201 @@
+package manifest
+
+import (
+	"github.com/osbuild/osbuild-composer/internal/container"
+	"github.com/osbuild/osbuild-composer/internal/osbuild"
+	"github.com/osbuild/osbuild-composer/internal/rpmmd"
+	"github.com/osbuild/osbuild-composer/internal/runner"
+)
+
+// BuildOptions carries the optional, backwards-compatible knobs for a
+// package-based build pipeline. A nil *BuildOptions behaves exactly as if no
+// options were given.
+type BuildOptions struct {
+	// ContainerBuildable adds the tools (podman, skopeo) and SELinux labels
+	// needed to build and deploy containers from within this build root.
+	ContainerBuildable bool
+
+	// ExtraPackages are appended to the set of packages the runner requires.
+	ExtraPackages []string
+
+	// ExcludePackages is passed through to the build root's PackageSet.Exclude
+	// and is honored by the depsolver, taking precedence over ExtraPackages
+	// and the runner's own requirements; it is not applied by getBuildPackages(),
+	// so that method's return value may still list a name that ends up excluded
+	// from the depsolved set.
+	ExcludePackages []string
+
+	// RunnerOverride replaces runner for the purposes of selecting build
+	// packages and the osbuild runner string, allowing a build root from one
+	// distro (e.g. EL9) to be used to produce an image for another (e.g.
+	// Fedora).
+	RunnerOverride runner.Runner
+
+	// Parent is the build pipeline this build pipeline is itself built in,
+	// for multi-stage bootstrap builds (e.g. an EL7 build root produced in an
+	// EL8 build root, itself produced in an EL9 build root). When nil, this
+	// build pipeline is built directly on the host filesystem.
+	Parent Build
+}
+
+// buildrootFromPackages is a Build implementation whose build root is
+// assembled from a resolved set of RPM packages, i.e. a regular dnf
+// transaction against the distro's repositories.
+type buildrootFromPackages struct {
+	Base
+
+	runner   runner.Runner
+	repos    []rpmmd.RepoConfig
+	options  *BuildOptions
+	packages []rpmmd.PackageSpec
+}
+
+// NewBuild creates a new build pipeline, named name, from the given
+// repositories. The pipeline installs the packages required by runner to
+// build the rest of the manifest's pipelines. opts may be nil, in which case
+// the build root is assembled exactly as if no options were given and is
+// built directly on the host filesystem.
+func NewBuild(m *Manifest, name string, runner runner.Runner, repos []rpmmd.RepoConfig, opts *BuildOptions) Build {
+	var parent Build
+	if opts != nil {
+		parent = opts.Parent
+	}
+	p := &buildrootFromPackages{
+		Base:    NewBase(m, name, parent),
+		runner:  runner,
+		repos:   repos,
+		options: opts,
+	}
+	m.addPipeline(p)
+	return p
+}
+
+// getRunner returns the runner to use for package selection and the osbuild
+// runner string, honouring BuildOptions.RunnerOverride when set.
+func (p *buildrootFromPackages) getRunner() runner.Runner {
+	if p.options != nil && p.options.RunnerOverride != nil {
+		return p.options.RunnerOverride
+	}
+	return p.runner
+}
+
+func (p *buildrootFromPackages) getBuildPackages() []string {
+	packages := p.getRunner().GetBuildPackages()
+	if p.options != nil {
+		if p.options.ContainerBuildable {
+			packages = append(packages, "podman", "skopeo")
+		}
+		packages = append(packages, p.options.ExtraPackages...)
+	}
+	return packages
+}
+
+func (p *buildrootFromPackages) getPackageSetChain() []rpmmd.PackageSet {
+	chain := rpmmd.PackageSet{
+		Include:      p.getBuildPackages(),
+		Repositories: p.repos,
+	}
+	if p.options != nil {
+		chain.Exclude = p.options.ExcludePackages
+	}
+	return []rpmmd.PackageSet{chain}
+}
+
+func (p *buildrootFromPackages) serializeStart(packages []rpmmd.PackageSpec) {
+	p.packages = packages
+}
+
+func (p *buildrootFromPackages) serializeEnd() {
+	p.packages = nil
+}
+
+func (p *buildrootFromPackages) getPackageSpecs() []rpmmd.PackageSpec {
+	return p.packages
+}
+
+func (p *buildrootFromPackages) serialize() osbuild.Pipeline {
+	if len(p.packages) == 0 {
+		panic("serialization not started")
+	}
+
+	pipeline := p.Base.serialize()
+	if !p.hasBuild() {
+		// Only the outermost build pipeline runs directly on the host
+		// filesystem and needs its runner autodetected; nested build
+		// pipelines run inside their parent's already-established
+		// environment.
+		pipeline.Runner = p.getRunner().String()
+	}
+	pipeline.AddStage(osbuild.NewRPMStage(osbuild.NewRPMStageOptions(p.repos), osbuild.NewRpmStageSourceFilesInputs(p.packages)))
+
+	selinuxOptions := &osbuild.SELinuxStageOptions{
+		FileContexts: "etc/selinux/targeted/contexts/files/file_contexts",
+	}
+	if p.options != nil && p.options.ContainerBuildable {
+		// podman needs to label the container storage itself; skopeo is
+		// installed but gets no label of its own
+		selinuxOptions.Labels = map[string]string{
+			"/usr/bin/podman": "system_u:object_r:container_runtime_exec_t:s0",
+		}
+	}
+	pipeline.AddStage(osbuild.NewSELinuxStage(selinuxOptions))
+
+	return pipeline
+}
+
+// buildrootFromContainer is a Build implementation whose build root is
+// extracted from a pre-built OCI container image rather than assembled from
+// individual RPM packages. It is used for bootc-style workflows where the
+// build tools are published as a container.
+type buildrootFromContainer struct {
+	Base
+
+	runner runner.Runner
+	source container.SourceSpec
+}
+
+// NewBuildFromContainerSpec creates a new build pipeline, named name, whose
+// build root is derived from the container image described by the given
+// source spec. The container is extracted at stage time and used in place of
+// a dnf-installed build root. parent is the build pipeline this build
+// pipeline is itself built in, for multi-stage bootstrap builds (see
+// BuildOptions.Parent); it may be nil, in which case this build pipeline is
+// built directly on the host filesystem.
+func NewBuildFromContainerSpec(m *Manifest, name string, runner runner.Runner, source container.SourceSpec, parent Build) Build {
+	p := &buildrootFromContainer{
+		Base:   NewBase(m, name, parent),
+		runner: runner,
+		source: source,
+	}
+	m.addPipeline(p)
+	return p
+}
+
+func (p *buildrootFromContainer) getContainerSpecs() []container.Spec {
+	return []container.Spec{
+		{
+			Source:  p.source.Source,
+			Digest:  p.source.Digest,
+			ImageID: p.source.Source,
+		},
+	}
+}
+
+func (p *buildrootFromContainer) serialize() osbuild.Pipeline {
+	containers := p.getContainerSpecs()
+	if len(containers) == 0 {
+		panic("serialization not started")
+	}
+
+	pipeline := p.Base.serialize()
+	if !p.hasBuild() {
+		// Only the outermost build pipeline runs directly on the host
+		// filesystem and needs its runner autodetected; nested build
+		// pipelines run inside their parent's already-established
+		// environment.
+		pipeline.Runner = p.runner.String()
+	}
+	pipeline.AddStage(osbuild.NewContainerDeployStage(osbuild.NewContainerDeployInputs(containers)))
+
+	return pipeline
+}
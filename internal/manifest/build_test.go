@@ -0,0 +1,178 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/osbuild/osbuild-composer/internal/container"
+	"github.com/osbuild/osbuild-composer/internal/rpmmd"
+)
+
+// testTreePipeline is a trivial Pipeline implementation used to exercise the
+// serialization of a build root without depending on a concrete OS pipeline
+// implementation.
+type testTreePipeline struct {
+	Base
+}
+
+func newTestTreePipeline(m *Manifest, build Build) *testTreePipeline {
+	p := &testTreePipeline{
+		Base: NewBase(m, "tree", build),
+	}
+	m.addPipeline(p)
+	return p
+}
+
+func TestBuildFromContainerSpecSerialize(t *testing.T) {
+	manifest := &Manifest{}
+	runner := &testRunner{}
+	source := container.SourceSpec{
+		Source: "registry.example.com/build-root",
+		Digest: "sha256:0000000000000000000000000000000000000000000000000000000000000",
+	}
+
+	build := NewBuildFromContainerSpec(manifest, "build", runner, source, nil)
+	tree := newTestTreePipeline(manifest, build)
+
+	buildPipeline := build.serialize()
+	assert.Equal(t, "build", buildPipeline.Name)
+	assert.Equal(t, runner.String(), buildPipeline.Runner)
+	require.Len(t, buildPipeline.Stages, 1)
+
+	tree.serializeStart([]rpmmd.PackageSpec{})
+	treePipeline := tree.serialize()
+	tree.serializeEnd()
+
+	assert.Equal(t, "name:build", treePipeline.Build)
+}
+
+func TestBuildFromContainerSpecNestedInParentBuild(t *testing.T) {
+	manifest := &Manifest{}
+	parentRunner := &testRunner{}
+	containerRunner := &testRunner{}
+	source := container.SourceSpec{
+		Source: "registry.example.com/build-root",
+		Digest: "sha256:0000000000000000000000000000000000000000000000000000000000000",
+	}
+
+	parent := NewBuild(manifest, "build-parent", parentRunner, nil, nil)
+	build := NewBuildFromContainerSpec(manifest, "build", containerRunner, source, parent)
+
+	parentPackages := parent.(*buildrootFromPackages)
+	parentPackages.serializeStart([]rpmmd.PackageSpec{{Name: "test-runner"}})
+	parentPipeline := parent.serialize()
+	parentPackages.serializeEnd()
+	assert.Equal(t, parentRunner.String(), parentPipeline.Runner)
+
+	buildPipeline := build.serialize()
+	assert.Equal(t, "name:build-parent", buildPipeline.Build)
+	assert.Empty(t, buildPipeline.Runner)
+}
+
+func TestBuildOptionsNilIsUnchanged(t *testing.T) {
+	manifest := &Manifest{}
+	build := NewBuild(manifest, "build", &testRunner{}, nil, nil).(*buildrootFromPackages)
+
+	assert.Equal(t, []string{"test-runner"}, build.getBuildPackages())
+	assert.Equal(t, []rpmmd.PackageSet{{Include: []string{"test-runner"}}}, build.getPackageSetChain())
+}
+
+func TestBuildOptionsContainerBuildable(t *testing.T) {
+	manifest := &Manifest{}
+	build := NewBuild(manifest, "build", &testRunner{}, nil, &BuildOptions{
+		ContainerBuildable: true,
+		ExtraPackages:      []string{"jq"},
+		ExcludePackages:    []string{"test-runner"},
+	}).(*buildrootFromPackages)
+
+	assert.Equal(t, []string{"test-runner", "podman", "skopeo", "jq"}, build.getBuildPackages())
+
+	chain := build.getPackageSetChain()
+	require.Len(t, chain, 1)
+	assert.Equal(t, []string{"test-runner"}, chain[0].Exclude)
+}
+
+func TestPlaceholderPackageSpecsHonorsExcludePackages(t *testing.T) {
+	manifest := &Manifest{}
+	build := NewBuild(manifest, "build", &testRunner{}, nil, &BuildOptions{
+		ExtraPackages:   []string{"jq"},
+		ExcludePackages: []string{"test-runner"},
+	}).(*buildrootFromPackages)
+
+	specs := placeholderPackageSpecs(build.getPackageSetChain())
+
+	names := make([]string, len(specs))
+	for i, s := range specs {
+		names[i] = s.Name
+	}
+	assert.Equal(t, []string{"jq"}, names)
+}
+
+func TestBuildOptionsRunnerOverride(t *testing.T) {
+	manifest := &Manifest{}
+	override := &testRunner{}
+	build := NewBuild(manifest, "build", nil, nil, &BuildOptions{RunnerOverride: override}).(*buildrootFromPackages)
+
+	assert.Equal(t, override.GetBuildPackages(), build.getBuildPackages())
+}
+
+func TestSerializeManifestOnly(t *testing.T) {
+	manifest := &Manifest{}
+	runner := &testRunner{}
+
+	build := NewBuild(manifest, "build", runner, nil, nil)
+	newTestTreePipeline(manifest, build)
+
+	osbuildManifest, err := manifest.SerializeManifestOnly()
+	require.NoError(t, err)
+	require.Len(t, osbuildManifest.Pipelines, 2)
+
+	buildPipeline := osbuildManifest.Pipelines[0]
+	require.Len(t, buildPipeline.Stages, 2)
+
+	treePipeline := osbuildManifest.Pipelines[1]
+	assert.Equal(t, "name:build", treePipeline.Build)
+}
+
+func TestNestedBuildChain(t *testing.T) {
+	manifest := &Manifest{}
+	el9Runner := &testRunner{}
+	el8Runner := &testRunner{}
+	el7Runner := &testRunner{}
+
+	el9Build := NewBuild(manifest, "build-el9", el9Runner, nil, nil)
+	el8Build := NewBuild(manifest, "build-el8", el8Runner, nil, &BuildOptions{Parent: el9Build})
+	el7Build := NewBuild(manifest, "build-el7", el7Runner, nil, &BuildOptions{Parent: el8Build})
+
+	osbuildManifest, err := manifest.SerializeManifestOnly()
+	require.NoError(t, err)
+	require.Len(t, osbuildManifest.Pipelines, 3)
+
+	el9Pipeline := osbuildManifest.Pipelines[0]
+	el8Pipeline := osbuildManifest.Pipelines[1]
+	el7Pipeline := osbuildManifest.Pipelines[2]
+
+	// Only the outermost build pipeline runs on the host and needs its
+	// runner autodetected.
+	assert.Equal(t, el9Runner.String(), el9Pipeline.Runner)
+	assert.Empty(t, el8Pipeline.Runner)
+	assert.Empty(t, el7Pipeline.Runner)
+
+	assert.Empty(t, el9Pipeline.Build)
+	assert.Equal(t, "name:build-el9", el8Pipeline.Build)
+	assert.Equal(t, "name:build-el8", el7Pipeline.Build)
+
+	assert.Equal(t, "build-el7", el7Build.Name())
+}
+
+type testRunner struct{}
+
+func (r *testRunner) String() string {
+	return "org.osbuild.test"
+}
+
+func (r *testRunner) GetBuildPackages() []string {
+	return []string{"test-runner"}
+}
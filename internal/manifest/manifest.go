@@ -0,0 +1,58 @@
+package manifest
+
+import (
+	"github.com/osbuild/osbuild-composer/internal/osbuild"
+)
+
+// Manifest represents a manifest and keeps track of its constituent pipelines.
+// Pipelines are added to a Manifest as they are constructed via their New*
+// constructors.
+type Manifest struct {
+	pipelines []Pipeline
+}
+
+// addPipeline appends a pipeline to the manifest. It is called by each
+// pipeline's constructor, after NewBase or NewBaseChecked has already
+// verified that the pipeline's name is unique, and should not be called
+// directly.
+func (m *Manifest) addPipeline(p Pipeline) {
+	m.pipelines = append(m.pipelines, p)
+}
+
+// PipelineByName returns the pipeline with the given name, and whether one
+// was found. It lets downstream code (e.g. the image package) discover the
+// pipeline graph without maintaining its own bookkeeping.
+func (m *Manifest) PipelineByName(name string) (Pipeline, bool) {
+	for _, p := range m.pipelines {
+		if p.Name() == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Pipelines returns all the pipelines added to the manifest so far, in the
+// order they were added. The returned slice is a copy; modifying it does not
+// affect the manifest.
+func (m *Manifest) Pipelines() []Pipeline {
+	pipelines := make([]Pipeline, len(m.pipelines))
+	copy(pipelines, m.pipelines)
+	return pipelines
+}
+
+// SerializeManifestOnly serializes every pipeline in the manifest using
+// placeholder package specs in place of a real depsolve. It is useful for CI
+// golden-manifest tests and workflows (e.g. otk) that need a deterministic
+// manifest per distro without touching the network. Use a pipeline's own
+// serializeStart()/serialize(), driven by the depsolve caller with real,
+// resolved package specs, when those are available instead.
+func (m *Manifest) SerializeManifestOnly() (osbuild.Manifest, error) {
+	pipelines := make([]osbuild.Pipeline, 0, len(m.pipelines))
+	for _, p := range m.pipelines {
+		p.serializeStart(placeholderPackageSpecs(p.getPackageSetChain()))
+		pipeline := p.serialize()
+		p.serializeEnd()
+		pipelines = append(pipelines, pipeline)
+	}
+	return osbuild.Manifest{Pipelines: pipelines}, nil
+}
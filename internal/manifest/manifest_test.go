@@ -0,0 +1,40 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBasePanicsOnDuplicateName(t *testing.T) {
+	manifest := &Manifest{}
+	newTestTreePipeline(manifest, nil)
+
+	assert.Panics(t, func() {
+		newTestTreePipeline(manifest, nil)
+	})
+}
+
+func TestNewBaseCheckedReturnsErrorOnDuplicateName(t *testing.T) {
+	manifest := &Manifest{}
+	newTestTreePipeline(manifest, nil)
+
+	_, err := NewBaseChecked(manifest, "tree", nil)
+	assert.ErrorContains(t, err, `pipeline name "tree" is already in use`)
+}
+
+func TestPipelineByNameAndPipelines(t *testing.T) {
+	manifest := &Manifest{}
+	build := NewBuild(manifest, "build", &testRunner{}, nil, nil)
+	tree := newTestTreePipeline(manifest, build)
+
+	require.Equal(t, []Pipeline{build, tree}, manifest.Pipelines())
+
+	found, ok := manifest.PipelineByName("tree")
+	assert.True(t, ok)
+	assert.Equal(t, tree, found)
+
+	_, ok = manifest.PipelineByName("does-not-exist")
+	assert.False(t, ok)
+}
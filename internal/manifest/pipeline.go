@@ -7,6 +7,8 @@
 package manifest
 
 import (
+	"fmt"
+
 	"github.com/osbuild/osbuild-composer/internal/artifact"
 	"github.com/osbuild/osbuild-composer/internal/container"
 	"github.com/osbuild/osbuild-composer/internal/osbuild"
@@ -32,12 +34,23 @@ type Pipeline interface {
 	getInline() []string
 }
 
+// Build represents a pipeline that can be used as the build root for another
+// pipeline. It is itself a Pipeline, but additionally exposes the manifest it
+// belongs to, so that NewBase can verify that a build pipeline was created for
+// the same manifest as the pipeline it builds. Build has two implementations:
+// one that derives the build root from a resolved set of RPM packages, and one
+// that derives it from a container image.
+type Build interface {
+	Pipeline
+	GetManifest() *Manifest
+}
+
 // A Base represents the core functionality shared between each of the pipeline
 // implementations, and the Base struct must be embedded in each of them.
 type Base struct {
 	manifest   *Manifest
 	name       string
-	build      *Build
+	build      Build
 	checkpoint bool
 	export     bool
 }
@@ -69,6 +82,12 @@ func (p Base) GetManifest() *Manifest {
 	return p.manifest
 }
 
+// hasBuild reports whether this pipeline has a build root of its own, as
+// opposed to running directly on the host filesystem.
+func (p Base) hasBuild() bool {
+	return p.build != nil
+}
+
 func (p Base) getBuildPackages() []string {
 	return []string{}
 }
@@ -94,25 +113,46 @@ func (p Base) getInline() []string {
 }
 
 // NewBase returns a generic Pipeline object. The name is mandatory, immutable and must
-// be unique among all the pipelines used in a manifest, which is currently not enforced.
+// be unique among all the pipelines used in a manifest; NewBase consults the manifest's
+// pipeline registry and panics on a duplicate, since a duplicate name would make
+// "name:<pipeline>" references in the resulting osbuild manifest ambiguous. Use
+// NewBaseChecked instead if the caller would rather handle the conflict as an error.
 // The build argument is a pipeline representing a build root in which the rest of the
 // pipeline is built. In order to ensure reproducibility a build pipeline must always be
 // provided, except for int he build pipeline itself. When a build pipeline is not provided
 // the build host's filesystem is used as the build root. The runner specifies how to use this
 // pipeline as a build pipeline, by naming the distro it contains. When the host system is used
 // as a build root, then the necessary runner is autodetected.
-func NewBase(m *Manifest, name string, build *Build) Base {
-	p := Base{
-		manifest: m,
-		name:     name,
-		build:    build,
+//
+// A build pipeline may itself have a build root (see BuildOptions.Parent), for multi-stage
+// bootstrap builds where e.g. an EL7 build root is produced in an EL8 build root, which is
+// itself produced in an EL9 build root. The manifest checks below effectively recurse through
+// such a chain, since every build pipeline in it was itself constructed through NewBase and
+// validated against the same manifest at that time.
+func NewBase(m *Manifest, name string, build Build) Base {
+	p, err := NewBaseChecked(m, name, build)
+	if err != nil {
+		panic(err)
 	}
+	return p
+}
+
+// NewBaseChecked is identical to NewBase, but returns an error instead of panicking
+// when name is already in use in m or build belongs to a different manifest.
+func NewBaseChecked(m *Manifest, name string, build Build) (Base, error) {
 	if build != nil {
-		if build.Base.manifest != m {
-			panic("build pipeline from a different manifest")
+		if build.GetManifest() != m {
+			return Base{}, fmt.Errorf("pipeline %q: build pipeline %q is from a different manifest", name, build.Name())
 		}
 	}
-	return p
+	if _, exists := m.PipelineByName(name); exists {
+		return Base{}, fmt.Errorf("pipeline name %q is already in use in this manifest", name)
+	}
+	return Base{
+		manifest: m,
+		name:     name,
+		build:    build,
+	}, nil
 }
 
 // serializeStart must be called exactly once before each call
@@ -138,6 +178,39 @@ func (p Base) serialize() osbuild.Pipeline {
 	return pipeline
 }
 
+// manifestOnlyChecksum is the placeholder checksum used for package specs
+// derived by placeholderPackageSpecs.
+const manifestOnlyChecksum = "sha256:0000000000000000000000000000000000000000000000000000000000000"
+
+// placeholderPackageSpecs derives a placeholder rpmmd.PackageSpec for every
+// package named in chain, so that a pipeline's serialize() can be exercised
+// without a real depsolve having taken place. Manifest.SerializeManifestOnly
+// passes its result to each pipeline's serializeStart() in place of the
+// resolved specs a real depsolve would provide; serializeStart() and
+// serialize() themselves are the same in both cases.
+// Names in a set's Exclude are skipped, mirroring what a real depsolve would
+// do with PackageSet.Exclude.
+func placeholderPackageSpecs(chain []rpmmd.PackageSet) []rpmmd.PackageSpec {
+	var specs []rpmmd.PackageSpec
+	for _, set := range chain {
+		excluded := make(map[string]bool, len(set.Exclude))
+		for _, name := range set.Exclude {
+			excluded[name] = true
+		}
+		for _, name := range set.Include {
+			if excluded[name] {
+				continue
+			}
+			specs = append(specs, rpmmd.PackageSpec{
+				Name:     name,
+				Version:  "0",
+				Checksum: manifestOnlyChecksum,
+			})
+		}
+	}
+	return specs
+}
+
 type Tree interface {
 	Name() string
 	GetManifest() *Manifest